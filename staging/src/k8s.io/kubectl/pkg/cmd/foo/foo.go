@@ -17,13 +17,24 @@ limitations under the License.
 package foo
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"text/template"
 
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
@@ -31,6 +42,8 @@ import (
 	"k8s.io/kubectl/pkg/util/templates"
 )
 
+const defaultNameTemplate = "{{.OriginalName}}-{{.Index}}"
+
 const defaultFilename = "default.yaml"
 
 var (
@@ -41,54 +54,104 @@ This is the foo command long description.
 	fooExample = templates.Examples(i18n.T(`
 		# Foo command example
 		kubectl foo --count 3 --filename foo-resource.yaml
+
+		# Foo every pod selected by a label query
+		kubectl foo pods -l app=nginx
+
+		# Foo all deployments in the current namespace
+		kubectl foo deployments --all
+
+		# Foo the resources built from a kustomization directory
+		kubectl foo -k dir/
 `))
 )
 
 // FooOptions are the knobs available for the "foo" command.
 type FooOptions struct {
-	Count            int
-	FilenameOptions  resource.FilenameOptions
-	namespace        string
-	enforceNamespace bool
-	PrintFlags       *genericclioptions.PrintFlags
+	Count                   int
+	FilenameOptions         resource.FilenameOptions
+	namespace               string
+	enforceNamespace        bool
+	PrintFlags              *genericclioptions.PrintFlags
+	CustomColumnsPrintFlags *genericclioptions.CustomColumnsPrintFlags
+
+	ServerSide     bool
+	ForceConflicts bool
+	FieldManager   string
+
+	DryRunStrategy cmdutil.DryRunStrategy
+
+	Parallelism     int
+	ContinueOnError bool
+
+	Selector      string
+	All           bool
+	FieldSelector string
+	Args          []string
+
+	NameTemplate string
+	GenerateName bool
 }
 
 // NewCmdFoo a new Cobra command encasulating the "foo" command.
 func NewCmdFoo(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
 	o := &FooOptions{
-		PrintFlags: genericclioptions.NewPrintFlags("created").WithDefaultOutput("name"),
+		PrintFlags:              genericclioptions.NewPrintFlags("created").WithDefaultOutput("name"),
+		CustomColumnsPrintFlags: genericclioptions.NewCustomColumnsPrintFlags(),
 	}
 
 	cmd := &cobra.Command{
-		Use: "foo [--count=COUNT] --filename=FILENAME",
+		Use: "foo (-f FILENAME | [-l label | --all] TYPE | TYPE/NAME)",
 		DisableFlagsInUseLine: true,
 		Short:   i18n.T("Foo short description"),
 		Long:    fooLong,
 		Example: fooExample,
 		Run: func(cmd *cobra.Command, args []string) {
-			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.Complete(f, cmd, args))
 			cmdutil.CheckErr(o.Validate())
 			cmdutil.CheckErr(o.RunFoo(f, ioStreams))
 		},
 	}
 
 	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.CustomColumnsPrintFlags.NoHeaders, "no-headers", o.CustomColumnsPrintFlags.NoHeaders, "When using the custom-columns output format, don't print headers (default print headers).")
 
 	cmd.Flags().IntVarP(&o.Count, "count", "c", o.Count, "Usage for count flag.")
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, "")
+	cmdutil.AddKustomizeFlag(cmd.Flags(), &o.FilenameOptions.Kustomize)
+	cmdutil.AddDryRunFlag(cmd)
+	cmdutil.AddServerSideApplyFlags(cmd)
+	cmdutil.AddFieldManagerFlagVar(cmd, &o.FieldManager, "kubectl-foo")
+	cmd.Flags().IntVar(&o.Parallelism, "parallelism", 1, "Number of objects to create concurrently. Defaults to 1 (sequential).")
+	cmd.Flags().BoolVar(&o.ContinueOnError, "continue-on-error", false, "If true, keep processing remaining objects after one fails instead of aborting the batch.")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) of the resources to operate on.")
+	cmd.Flags().BoolVar(&o.All, "all", o.All, "Select all resources of the given type in the namespace.")
+	cmd.Flags().StringVar(&o.FieldSelector, "field-selector", o.FieldSelector, "Selector (field query) of the resources to operate on, supports '=', '==', and '!='.")
+	cmd.Flags().StringVar(&o.NameTemplate, "name-template", defaultNameTemplate, "Go template used to name each of the --count copies of an object. Has .Index, .OriginalName, and .Namespace in scope.")
+	cmd.Flags().BoolVar(&o.GenerateName, "generate-name", o.GenerateName, "Instead of naming copies with --name-template, set metadata.generateName from the original name and let the apiserver assign a unique suffix.")
 
 	return cmd
 }
 
 // Complete fills in all the FooOptions fields, including defaults.
-func (o *FooOptions) Complete(f cmdutil.Factory, args []string) error {
+func (o *FooOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
 
 	var err error
+	o.Args = args
 	o.namespace, o.enforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
 	if err != nil {
 		return err
 	}
 
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	o.ServerSide = cmdutil.GetFlagBool(cmd, "server-side")
+	o.ForceConflicts = cmdutil.GetFlagBool(cmd, "force-conflicts")
+
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+
 	return nil
 }
 
@@ -98,9 +161,60 @@ func (o *FooOptions) Validate() error {
 		return fmt.Errorf("Count is negative")
 	}
 
+	if o.Parallelism < 1 {
+		return fmt.Errorf("Parallelism must be at least 1")
+	}
+
+	if o.GenerateName && o.ServerSide {
+		return fmt.Errorf("cannot specify --generate-name with --server-side: server-side apply requires a name")
+	}
+
+	hasKustomize := len(o.FilenameOptions.Kustomize) > 0
+	hasFilename := len(o.FilenameOptions.Filenames) > 0 || hasKustomize
+	hasType := len(o.Args) > 0
+	hasNameInType := hasType && (strings.Contains(o.Args[0], "/") || len(o.Args) > 1)
+	hasSelector := len(o.Selector) > 0 || len(o.FieldSelector) > 0
+
+	if hasKustomize && (len(o.FilenameOptions.Filenames) > 0 || o.FilenameOptions.Recursive) {
+		return fmt.Errorf("cannot specify -k/--kustomize together with -f/--filename or -R/--recursive")
+	}
+
+	switch {
+	case hasFilename && hasType:
+		return fmt.Errorf("cannot specify both -f/--filename and a resource type")
+	case !hasFilename && !hasType:
+		return fmt.Errorf("must specify -f/--filename, TYPE/NAME, or TYPE with -l/--selector or --all")
+	case hasType && !hasNameInType && !o.All && !hasSelector:
+		return fmt.Errorf("you must specify -l/--selector or --all with TYPE, or use TYPE/NAME")
+	case hasNameInType && (o.All || hasSelector):
+		return fmt.Errorf("cannot specify --all or -l/--selector when TYPE/NAME is used")
+	}
+
 	return nil
 }
 
+// ToPrinter builds the printer for the configured -o format, falling back to
+// custom-columns (including custom-columns-file) when o.PrintFlags doesn't
+// recognize the format. This lets -o custom-columns=... and -o
+// custom-columns-file=... work the same way -o jsonpath=... and -o
+// go-template=... already do through the composed genericclioptions.PrintFlags.
+func (o *FooOptions) ToPrinter() (printers.ResourcePrinter, error) {
+	outputFormat := ""
+	if o.PrintFlags.OutputFormat != nil {
+		outputFormat = *o.PrintFlags.OutputFormat
+	}
+
+	printer, err := o.PrintFlags.ToPrinter()
+	if genericclioptions.IsNoCompatiblePrinterError(err) {
+		printer, err = o.CustomColumnsPrintFlags.ToPrinter(outputFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return printer, nil
+}
+
 // RunFoo executes the foo command.
 func (o *FooOptions) RunFoo(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) error {
 
@@ -119,6 +233,10 @@ func (o *FooOptions) RunFoo(f cmdutil.Factory, ioStreams genericclioptions.IOStr
 		ContinueOnError().
 		NamespaceParam(o.namespace).DefaultNamespace().
 		FilenameParam(o.enforceNamespace, &o.FilenameOptions).
+		LabelSelectorParam(o.Selector).
+		FieldSelectorParam(o.FieldSelector).
+		SelectAllParam(o.All).
+		ResourceTypeOrNameArgs(true, o.Args...).
 		Flatten().
 		Do()
 	err := r.Err()
@@ -126,36 +244,248 @@ func (o *FooOptions) RunFoo(f cmdutil.Factory, ioStreams genericclioptions.IOStr
 		return err
 	}
 
-	printer, err := o.PrintFlags.ToPrinter()
+	printer, err := o.ToPrinter()
 	if err != nil {
 		return err
 	}
 
-	// Iterate through the result objects (in the resource.Info).
-	var obj runtime.Object
+	// Collect every object yielded by the builder up front so each one can be
+	// created independently; ContinueOnError() above means infos may already
+	// be a partial list with r.Err() reporting the rest. With
+	// --continue-on-error, a bad manifest is skipped rather than aborting the
+	// whole command, but it's still recorded so it surfaces in the final
+	// aggregated error and exit code instead of being silently dropped.
+	var infos []*resource.Info
+	var visitErrs []error
 	err = r.Visit(func(info *resource.Info, err error) error {
-		if err == nil {
-			obj = info.Object
-
-			// Create the resource helper. The parameters are a RESTMapping
-			// (essentially a GVK), and a RESTClient (created by the
-			// factory.ClientForMapping() method).
-			mapping := info.ResourceMapping()
-			client, err := f.ClientForMapping(mapping)
-			if err != nil {
-				return err
+		if err != nil {
+			if o.ContinueOnError {
+				visitErrs = append(visitErrs, err)
+				return nil
 			}
-			helper := resource.NewHelper(client, mapping)
+			return err
+		}
+		infos = append(infos, info)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-			// Using the resource helper, create the decoded object on the APIServer.
-			_, err = helper.Create(info.Namespace, true, obj, &metav1.CreateOptions{})
+	infos, err = o.expandInfos(infos)
+	if err != nil {
+		return err
+	}
+
+	// Fan the Create calls for each object out across o.Parallelism workers,
+	// collecting a per-object error instead of aborting the whole batch on
+	// the first failure. Printers are stateful (e.g. custom-columns tracks
+	// whether the header has been written), so printing happens serially
+	// after all workers finish rather than concurrently from within them, in
+	// input order rather than worker-completion order. Unless
+	// --continue-on-error is set, the first create error cancels ctx so the
+	// remaining queued objects are skipped instead of also created.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type indexedInfo struct {
+		index int
+		info  *resource.Info
+	}
+
+	var (
+		mu      sync.Mutex
+		results = make([]runtime.Object, len(infos))
+		errs    = make([]error, len(infos))
+		infoCh  = make(chan indexedInfo)
+		wg      sync.WaitGroup
+	)
+
+	worker := func() {
+		defer wg.Done()
+		for item := range infoCh {
+			if ctx.Err() != nil {
+				continue
+			}
+			obj, err := o.createObject(f, item.info)
 			if err != nil {
-				return err
+				mu.Lock()
+				errs[item.index] = err
+				mu.Unlock()
+				if !o.ContinueOnError {
+					cancel()
+				}
+				continue
 			}
+			mu.Lock()
+			results[item.index] = obj
+			mu.Unlock()
+		}
+	}
+
+	workers := o.Parallelism
+	if workers > len(infos) {
+		workers = len(infos)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker()
+	}
+dispatch:
+	for i, info := range infos {
+		select {
+		case infoCh <- indexedInfo{index: i, info: info}:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(infoCh)
+	wg.Wait()
+
+	for _, obj := range results {
+		if obj != nil {
 			printer.PrintObj(obj, ioStreams.Out)
 		}
-		return nil
-	})
+	}
+
+	allErrs := visitErrs
+	for _, err := range errs {
+		if err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	if len(allErrs) > 0 {
+		return utilerrors.NewAggregate(allErrs)
+	}
 
 	return nil
 }
+
+// createObject creates (or server-side applies) a single decoded object,
+// honoring the configured dry-run strategy, and returns the object that
+// should be handed to the printer.
+func (o *FooOptions) createObject(f cmdutil.Factory, info *resource.Info) (runtime.Object, error) {
+	obj := info.Object
+
+	if o.DryRunStrategy == cmdutil.DryRunClient {
+		return obj, nil
+	}
+
+	// Create the resource helper. The parameters are a RESTMapping
+	// (essentially a GVK), and a RESTClient (created by the
+	// factory.ClientForMapping() method).
+	mapping := info.ResourceMapping()
+	client, err := f.ClientForMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+	helper := resource.NewHelper(client, mapping)
+
+	var dryRun []string
+	if o.DryRunStrategy == cmdutil.DryRunServer {
+		dryRun = []string{metav1.DryRunAll}
+	}
+
+	if o.ServerSide {
+		// Decoding through the typed scheme clears TypeMeta, but an apply
+		// PATCH body must carry apiVersion/kind or the apiserver rejects it.
+		obj.GetObjectKind().SetGroupVersionKind(mapping.GroupVersionKind)
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		return helper.Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{
+			DryRun:       dryRun,
+			Force:        &o.ForceConflicts,
+			FieldManager: o.FieldManager,
+		})
+	}
+
+	return helper.Create(info.Namespace, true, obj, &metav1.CreateOptions{
+		DryRun:       dryRun,
+		FieldManager: o.FieldManager,
+	})
+}
+
+// nameTemplateData is the data made available to --name-template.
+type nameTemplateData struct {
+	Index        int
+	OriginalName string
+	Namespace    string
+}
+
+// expandInfos turns each input info into o.Count copies (1 if o.Count is
+// unset), deep-copying the decoded object and assigning each copy a new name
+// per --name-template, or a shared --generate-name prefix. With o.Count unset
+// and no --generate-name, infos is returned unchanged.
+func (o *FooOptions) expandInfos(infos []*resource.Info) ([]*resource.Info, error) {
+	count := o.Count
+	if count < 1 {
+		count = 1
+	}
+	if o.Count < 1 && !o.GenerateName {
+		return infos, nil
+	}
+
+	tmpl, err := template.New("name-template").Parse(o.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --name-template: %v", err)
+	}
+
+	type key struct{ namespace, name string }
+	seen := make(map[key]bool)
+
+	var expanded []*resource.Info
+	for _, info := range infos {
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return nil, err
+		}
+		originalName := accessor.GetName()
+
+		for i := 0; i < count; i++ {
+			copied := info.Object.DeepCopyObject()
+			copiedAccessor, err := meta.Accessor(copied)
+			if err != nil {
+				return nil, err
+			}
+
+			newInfo := *info
+			newInfo.Object = copied
+
+			if o.GenerateName {
+				copiedAccessor.SetName("")
+				copiedAccessor.SetGenerateName(originalName + "-")
+				newInfo.Name = ""
+				expanded = append(expanded, &newInfo)
+				continue
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, nameTemplateData{
+				Index:        i,
+				OriginalName: originalName,
+				Namespace:    accessor.GetNamespace(),
+			}); err != nil {
+				return nil, fmt.Errorf("error executing --name-template: %v", err)
+			}
+			name := buf.String()
+
+			if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+				return nil, fmt.Errorf("generated name %q is invalid: %s", name, strings.Join(errs, ", "))
+			}
+
+			k := key{namespace: accessor.GetNamespace(), name: name}
+			if seen[k] {
+				return nil, fmt.Errorf("generated name %q collides with another object in namespace %q; adjust --name-template", name, k.namespace)
+			}
+			seen[k] = true
+
+			copiedAccessor.SetName(name)
+			newInfo.Name = name
+			expanded = append(expanded, &newInfo)
+		}
+	}
+
+	return expanded, nil
+}
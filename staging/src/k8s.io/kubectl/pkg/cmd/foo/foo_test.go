@@ -0,0 +1,300 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package foo
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// TestValidateSelection covers the -f/--filename vs TYPE[/NAME] vs
+// -l/--selector/--all resource-selection matrix.
+func TestValidateSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    FooOptions
+		wantErr bool
+	}{
+		{
+			name: "filename alone is valid",
+			opts: FooOptions{Parallelism: 1, FilenameOptions: resource.FilenameOptions{Filenames: []string{"foo.yaml"}}},
+		},
+		{
+			name: "type/name alone is valid",
+			opts: FooOptions{Parallelism: 1, Args: []string{"pods/my-pod"}},
+		},
+		{
+			name: "space-separated TYPE NAME is valid",
+			opts: FooOptions{Parallelism: 1, Args: []string{"pods", "my-pod"}},
+		},
+		{
+			name: "space-separated TYPE with multiple NAMEs is valid",
+			opts: FooOptions{Parallelism: 1, Args: []string{"pods", "my-pod", "other-pod"}},
+		},
+		{
+			name: "type with --all is valid",
+			opts: FooOptions{Parallelism: 1, Args: []string{"pods"}, All: true},
+		},
+		{
+			name: "type with --selector is valid",
+			opts: FooOptions{Parallelism: 1, Args: []string{"pods"}, Selector: "app=nginx"},
+		},
+		{
+			name:    "filename and type together is an error",
+			opts:    FooOptions{Parallelism: 1, FilenameOptions: resource.FilenameOptions{Filenames: []string{"foo.yaml"}}, Args: []string{"pods"}},
+			wantErr: true,
+		},
+		{
+			name:    "neither filename nor type is an error",
+			opts:    FooOptions{Parallelism: 1},
+			wantErr: true,
+		},
+		{
+			name:    "bare type with no selector/--all is an error",
+			opts:    FooOptions{Parallelism: 1, Args: []string{"pods"}},
+			wantErr: true,
+		},
+		{
+			name:    "type/name with --all is an error",
+			opts:    FooOptions{Parallelism: 1, Args: []string{"pods/my-pod"}, All: true},
+			wantErr: true,
+		},
+		{
+			name:    "type/name with --selector is an error",
+			opts:    FooOptions{Parallelism: 1, Args: []string{"pods/my-pod"}, Selector: "app=nginx"},
+			wantErr: true,
+		},
+		{
+			name:    "space-separated TYPE NAME with --all is an error",
+			opts:    FooOptions{Parallelism: 1, Args: []string{"pods", "my-pod"}, All: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestValidateKustomize covers the -k/--kustomize combinations with
+// -f/--filename and -R/--recursive.
+func TestValidateKustomize(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    FooOptions
+		wantErr bool
+	}{
+		{
+			name: "kustomize alone is valid",
+			opts: FooOptions{Parallelism: 1, FilenameOptions: resource.FilenameOptions{Kustomize: "dir/"}},
+		},
+		{
+			name:    "kustomize with filename is an error",
+			opts:    FooOptions{Parallelism: 1, FilenameOptions: resource.FilenameOptions{Kustomize: "dir/", Filenames: []string{"foo.yaml"}}},
+			wantErr: true,
+		},
+		{
+			name:    "kustomize with recursive is an error",
+			opts:    FooOptions{Parallelism: 1, FilenameOptions: resource.FilenameOptions{Kustomize: "dir/", Recursive: true}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestValidateGenerateNameServerSide verifies that --generate-name is
+// rejected together with --server-side, since an apply PATCH targets a
+// specific name and generate-name leaves the name for the apiserver to pick.
+func TestValidateGenerateNameServerSide(t *testing.T) {
+	opts := FooOptions{
+		Parallelism:  1,
+		Args:         []string{"pods"},
+		All:          true,
+		GenerateName: true,
+		ServerSide:   true,
+	}
+	if err := opts.Validate(); err == nil {
+		t.Fatal("expected an error combining --generate-name with --server-side, got nil")
+	}
+
+	opts.ServerSide = false
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("unexpected error without --server-side: %v", err)
+	}
+}
+
+// TestExpandInfos covers --count name-templating, --generate-name, and the
+// DNS-1123/collision checks applied to templated names.
+func TestExpandInfos(t *testing.T) {
+	newInfo := func(namespace, name string) *resource.Info {
+		return &resource.Info{
+			Namespace: namespace,
+			Name:      name,
+			Object: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      name,
+						"namespace": namespace,
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("unset count without generate-name is a no-op", func(t *testing.T) {
+		o := &FooOptions{NameTemplate: defaultNameTemplate}
+		infos := []*resource.Info{newInfo("default", "my-obj")}
+
+		expanded, err := o.expandInfos(infos)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(expanded) != 1 || expanded[0] != infos[0] {
+			t.Fatalf("expected the input slice to be returned unchanged, got %#v", expanded)
+		}
+	})
+
+	t.Run("count expands with the name template", func(t *testing.T) {
+		o := &FooOptions{Count: 3, NameTemplate: defaultNameTemplate}
+		infos := []*resource.Info{newInfo("default", "my-obj")}
+
+		expanded, err := o.expandInfos(infos)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(expanded) != 3 {
+			t.Fatalf("expected 3 copies, got %d", len(expanded))
+		}
+		for i, info := range expanded {
+			want := fmt.Sprintf("my-obj-%d", i)
+			if info.Name != want {
+				t.Errorf("copy %d: expected name %q, got %q", i, want, info.Name)
+			}
+		}
+	})
+
+	t.Run("generate-name clears the name and sets metadata.generateName", func(t *testing.T) {
+		o := &FooOptions{Count: 2, GenerateName: true, NameTemplate: defaultNameTemplate}
+		infos := []*resource.Info{newInfo("default", "my-obj")}
+
+		expanded, err := o.expandInfos(infos)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, info := range expanded {
+			if info.Name != "" {
+				t.Errorf("expected empty Name with --generate-name, got %q", info.Name)
+			}
+			accessor, err := meta.Accessor(info.Object)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := accessor.GetGenerateName(), "my-obj-"; got != want {
+				t.Errorf("expected generateName %q, got %q", want, got)
+			}
+		}
+	})
+
+	t.Run("invalid templated name is rejected", func(t *testing.T) {
+		o := &FooOptions{Count: 1, NameTemplate: "Not_A-Valid.Name"}
+		infos := []*resource.Info{newInfo("default", "my-obj")}
+
+		if _, err := o.expandInfos(infos); err == nil {
+			t.Fatal("expected an error for a DNS-1123-invalid templated name, got nil")
+		}
+	})
+
+	t.Run("colliding templated names across infos are rejected", func(t *testing.T) {
+		o := &FooOptions{Count: 1, NameTemplate: "shared-name"}
+		infos := []*resource.Info{newInfo("default", "a"), newInfo("default", "b")}
+
+		if _, err := o.expandInfos(infos); err == nil {
+			t.Fatal("expected an error for colliding templated names, got nil")
+		}
+	})
+}
+
+// TestToPrinterCustomColumnsFallback verifies that -o custom-columns=...
+// falls back to CustomColumnsPrintFlags when it isn't a format PrintFlags
+// itself recognizes.
+func TestToPrinterCustomColumnsFallback(t *testing.T) {
+	outputFormat := "custom-columns=NAME:.metadata.name"
+	o := &FooOptions{
+		PrintFlags:              genericclioptions.NewPrintFlags("created").WithDefaultOutput("name"),
+		CustomColumnsPrintFlags: genericclioptions.NewCustomColumnsPrintFlags(),
+	}
+	o.PrintFlags.OutputFormat = &outputFormat
+
+	printer, err := o.ToPrinter()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if printer == nil {
+		t.Fatal("expected a non-nil printer")
+	}
+}
+
+// TestCreateObjectClientDryRun verifies that --dry-run=client short-circuits
+// createObject before it ever touches a client, returning the decoded object
+// unchanged regardless of --server-side/--force-conflicts.
+func TestCreateObjectClientDryRun(t *testing.T) {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "my-obj"},
+		},
+	}
+	info := &resource.Info{Name: "my-obj", Object: obj}
+
+	o := &FooOptions{
+		DryRunStrategy: cmdutil.DryRunClient,
+		ServerSide:     true,
+		ForceConflicts: true,
+	}
+
+	got, err := o.createObject(nil, info)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != obj {
+		t.Fatalf("expected createObject to return the decoded object unchanged, got %#v", got)
+	}
+}